@@ -0,0 +1,253 @@
+// bible.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Translations holds all user‐facing strings loaded from index.txt frontmatter.
+type Translations struct {
+	LanguageCode          string `yaml:"language"`
+	InvalidParamTitle     string `yaml:"invalidParamTitle"`
+	InvalidParamMessage   string `yaml:"invalidParamMessage"`
+	AcceptedValuesMessage string `yaml:"acceptedValuesMessage"`
+	NoVersesError         string `yaml:"noVersesError"`
+	BookNotFoundError     string `yaml:"bookNotFoundError"`
+	DecompressionError    string `yaml:"decompressionError"`
+	VersePageTitleFormat  string `yaml:"versePageTitleFormat"`
+}
+
+type BookInfo struct {
+	ID        int
+	Name      string
+	LineCount int
+	File      string
+}
+
+// categories are the named book-ID ranges shared by every translation; the
+// book-ID scheme (10 = Genesis … 730 = Revelation) is a convention of the
+// corpus layout, not something any one translation's index.txt defines.
+var categories = []struct {
+	Key           string
+	LowID, HighID int
+}{
+	{"ot", 10, 460},
+	{"nt", 470, 730},
+	{"pentateuch", 10, 50},
+	{"historical", 60, 190},
+	{"poetic", 220, 260},
+	{"major", 290, 340},
+	{"minor", 350, 460},
+	{"gospels", 470, 500},
+	{"apostolic", 510, 720},
+	{"acts", 510, 510},
+	{"paul", 520, 650},
+	{"general", 660, 720},
+	{"revelation", 730, 730},
+}
+
+// Bible is one loaded translation: its own corpus filesystem, its
+// frontmatter-derived strings, its book list, and the category lookups and
+// verseReader built from them.
+type Bible struct {
+	Translations
+
+	fsys      fs.FS
+	books     []BookInfo
+	vr        *verseReader
+	catMap    map[string]struct{ LowID, HighID int }
+	catLabel  map[string]string
+	searchIdx *searchIndex
+}
+
+// LoadBible reads index.txt (with its YAML frontmatter) from fsys and
+// builds the book list, category maps, and verseReader for one
+// translation's corpus. fsys must also contain each book's N.txt.gz and
+// sibling N.idx at its root.
+func LoadBible(fsys fs.FS) (*Bible, error) {
+	raw, err := fs.ReadFile(fsys, "index.txt")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read index.txt: %w", err)
+	}
+
+	b := &Bible{fsys: fsys}
+
+	var content []byte
+	if bytes.HasPrefix(raw, []byte("---\n")) {
+		parts := bytes.SplitN(raw, []byte("\n---\n"), 2)
+		if err := yaml.Unmarshal(parts[0], &b.Translations); err != nil {
+			return nil, fmt.Errorf("failed to parse translations: %w", err)
+		}
+		content = parts[1]
+	} else {
+		content = raw
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		cnt, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		b.books = append(b.books, BookInfo{
+			ID:        id,
+			Name:      parts[1],
+			LineCount: cnt,
+			File:      fmt.Sprintf("%d.txt.gz", id),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading index content: %w", err)
+	}
+
+	b.catMap = make(map[string]struct{ LowID, HighID int }, len(categories))
+	b.catLabel = make(map[string]string, len(categories))
+	for _, c := range categories {
+		b.catMap[c.Key] = struct{ LowID, HighID int }{c.LowID, c.HighID}
+		slice := b.sliceRange(c.LowID, c.HighID)
+		if len(slice) == 0 {
+			b.catLabel[c.Key] = ""
+		} else if c.LowID == c.HighID {
+			b.catLabel[c.Key] = slice[0].Name
+		} else {
+			b.catLabel[c.Key] = slice[0].Name + " — " + slice[len(slice)-1].Name
+		}
+	}
+
+	b.vr = newVerseReader(fsys, "")
+
+	// search.gob is optional: a corpus built before searchgen existed, or
+	// one a caller assembled by hand, simply can't be searched.
+	if raw, err := fs.ReadFile(fsys, "search.gob"); err == nil {
+		var idx searchIndex
+		if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&idx); err == nil {
+			b.searchIdx = &idx
+		}
+	}
+
+	return b, nil
+}
+
+// sliceRange returns b's books whose ID ∈ [lowID…highID].
+func (b *Bible) sliceRange(lowID, highID int) []BookInfo {
+	start, end := -1, -1
+	for i, bk := range b.books {
+		if start < 0 && bk.ID >= lowID {
+			start = i
+		}
+		if bk.ID <= highID {
+			end = i
+		}
+		if bk.ID > highID {
+			break
+		}
+	}
+	if start >= 0 && end >= start {
+		return b.books[start : end+1]
+	}
+	return nil
+}
+
+// gzipHeaderLen is the size of a gzip header with no extra/name/comment
+// fields set (ID1 ID2 CM FLG MTIME(4) XFL OS), which is how indexgen writes
+// the recompressed books embedded below.
+const gzipHeaderLen = 10
+
+// verseCheckpoint and bookIndex mirror the types indexgen gob-encodes into
+// each book's sibling .idx file; see randfromkjv/indexgen for how they're
+// produced.
+type verseCheckpoint struct {
+	UncompressedOffset int64
+	CompressedOffset   int64
+	Dictionary         []byte
+}
+
+type bookIndex struct {
+	LineOffsets []int64
+	Checkpoints []verseCheckpoint
+}
+
+// verseReader provides random access into a gzip-packed book by seeking to
+// the nearest precomputed checkpoint in its sibling .idx file instead of
+// decompressing the book from the start.
+type verseReader struct {
+	fsys fs.FS
+	dir  string
+}
+
+func newVerseReader(fsys fs.FS, dir string) *verseReader {
+	return &verseReader{fsys: fsys, dir: dir}
+}
+
+func (vr *verseReader) loadIndex(sel BookInfo) (*bookIndex, error) {
+	raw, err := fs.ReadFile(vr.fsys, vr.dir+strings.TrimSuffix(sel.File, ".txt.gz")+".idx")
+	if err != nil {
+		return nil, err
+	}
+	var idx bookIndex
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// lineScanner returns a scanner whose first Scan() call yields the
+// 1-indexed line lineNo of book sel, without decompressing sel from the
+// start of its gzip stream.
+func (vr *verseReader) lineScanner(sel BookInfo, lineNo int) (*bufio.Scanner, error) {
+	idx, err := vr.loadIndex(sel)
+	if err != nil {
+		return nil, err
+	}
+	if lineNo < 1 || lineNo > len(idx.LineOffsets) {
+		return nil, fmt.Errorf("line %d out of range for %s", lineNo, sel.Name)
+	}
+	target := idx.LineOffsets[lineNo-1]
+
+	cp := idx.Checkpoints[0]
+	for _, c := range idx.Checkpoints {
+		if c.UncompressedOffset > target {
+			break
+		}
+		cp = c
+	}
+
+	f, err := vr.fsys.Open(vr.dir + sel.File)
+	if err != nil {
+		return nil, err
+	}
+	seeker, ok := f.(io.Seeker)
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("%s does not support seeking", sel.File)
+	}
+	if _, err := seeker.Seek(gzipHeaderLen+cp.CompressedOffset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	fr := flate.NewReaderDict(bufio.NewReader(f), cp.Dictionary)
+	if _, err := io.CopyN(io.Discard, fr, target-cp.UncompressedOffset); err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	return bufio.NewScanner(fr), nil
+}
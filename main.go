@@ -2,216 +2,89 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"compress/gzip"
 	"embed"
 	"flag"
 	"fmt"
 	"html"
+	"io/fs"
 	"log"
-	"math/rand"
 	"net/http"
-	"strconv"
+	"net/url"
+	"os"
 	"strings"
-	"time"
-
-	"gopkg.in/yaml.v2"
 )
 
 //go:embed randfromkjv/index.txt
 //go:embed randfromkjv/*.txt.gz
-var bibleFS embed.FS
-
-// Translations holds all user‐facing strings loaded from index.txt frontmatter.
-type Translations struct {
-	LanguageCode          string `yaml:"language"`
-	InvalidParamTitle     string `yaml:"invalidParamTitle"`
-	InvalidParamMessage   string `yaml:"invalidParamMessage"`
-	AcceptedValuesMessage string `yaml:"acceptedValuesMessage"`
-	NoVersesError         string `yaml:"noVersesError"`
-	BookNotFoundError     string `yaml:"bookNotFoundError"`
-	DecompressionError    string `yaml:"decompressionError"`
-	VersePageTitleFormat  string `yaml:"versePageTitleFormat"`
-}
+//go:embed randfromkjv/*.idx
+//go:embed randfromkjv/*.gob
+var embeddedKJV embed.FS
 
 var (
-	trans      Translations
-	books      []BookInfo
-	rng        *rand.Rand
-	categories []struct {
-		Key           string
-		LowID, HighID int
-	}
-	catMap   map[string]struct{ LowID, HighID int }
-	catLabel map[string]string
+	// bibles holds every loaded translation, keyed by its frontmatter
+	// language code. defaultCode selects which one serves requests that
+	// don't specify ?t=.
+	bibles      map[string]*Bible
+	defaultCode string
 )
 
-type BookInfo struct {
-	ID        int
-	Name      string
-	LineCount int
-	File      string
-}
-
 func init() {
-	// seed RNG
-	rng = rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	// read index.txt (with YAML frontmatter)
-	raw, err := bibleFS.ReadFile("randfromkjv/index.txt")
+	kjvFS, err := fs.Sub(embeddedKJV, "randfromkjv")
 	if err != nil {
-		log.Fatalf("cannot read index.txt: %v", err)
-	}
-
-	// split YAML frontmatter from content
-	var content []byte
-	if bytes.HasPrefix(raw, []byte("---\n")) {
-		parts := bytes.SplitN(raw, []byte("\n---\n"), 2)
-		if err := yaml.Unmarshal(parts[0], &trans); err != nil {
-			log.Fatalf("failed to parse translations: %v", err)
-		}
-		content = parts[1]
-	} else {
-		log.Println("warning: no frontmatter found, using defaults")
-		content = raw
-	}
-
-	// scan book index lines
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	for scanner.Scan() {
-		parts := strings.Split(scanner.Text(), "|")
-		if len(parts) != 3 {
-			continue
-		}
-		id, err := strconv.Atoi(parts[0])
-		if err != nil {
-			continue
-		}
-		cnt, err := strconv.Atoi(parts[2])
-		if err != nil {
-			continue
-		}
-		books = append(books, BookInfo{
-			ID:        id,
-			Name:      parts[1],
-			LineCount: cnt,
-			File:      fmt.Sprintf("%d.txt.gz", id),
-		})
+		log.Fatalf("cannot scope embedded KJV filesystem: %v", err)
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("error reading index content: %v", err)
-	}
-
-	// define named ranges
-	categories = []struct {
-		Key           string
-		LowID, HighID int
-	}{
-		{"ot", 10, 460},
-		{"nt", 470, 730},
-		{"pentateuch", 10, 50},
-		{"historical", 60, 190},
-		{"poetic", 220, 260},
-		{"major", 290, 340},
-		{"minor", 350, 460},
-		{"gospels", 470, 500},
-		{"apostolic", 510, 720},
-		{"acts", 510, 510},
-		{"paul", 520, 650},
-		{"general", 660, 720},
-		{"revelation", 730, 730},
+	kjv, err := LoadBible(kjvFS)
+	if err != nil {
+		log.Fatalf("cannot load embedded KJV: %v", err)
 	}
 
-	// build lookup maps
-	catMap = make(map[string]struct{ LowID, HighID int }, len(categories))
-	catLabel = make(map[string]string, len(categories))
-	for _, c := range categories {
-		catMap[c.Key] = struct{ LowID, HighID int }{c.LowID, c.HighID}
-		slice := sliceRange(c.LowID, c.HighID)
-		if len(slice) == 0 {
-			catLabel[c.Key] = ""
-		} else if c.LowID == c.HighID {
-			catLabel[c.Key] = slice[0].Name
-		} else {
-			first := slice[0].Name
-			last := slice[len(slice)-1].Name
-			catLabel[c.Key] = first + " — " + last
-		}
-	}
+	bibles = map[string]*Bible{kjv.LanguageCode: kjv}
+	defaultCode = kjv.LanguageCode
 }
 
-// sliceRange returns books whose ID ∈ [lowID…highID].
-func sliceRange(lowID, highID int) []BookInfo {
-	start, end := -1, -1
-	for i, b := range books {
-		if start < 0 && b.ID >= lowID {
-			start = i
-		}
-		if b.ID <= highID {
-			end = i
-		}
-		if b.ID > highID {
-			break
-		}
-	}
-	if start >= 0 && end >= start {
-		return books[start : end+1]
+// bibleFor resolves the translation requested via code (the ?t= query
+// param, or a /t/{code}/... path segment), falling back to defaultCode
+// when code is empty.
+func bibleFor(code string) (*Bible, bool) {
+	if code == "" {
+		code = defaultCode
 	}
-	return nil
+	b, ok := bibles[code]
+	return b, ok
 }
 
-func randomHandler(w http.ResponseWriter, r *http.Request) {
+// randomHandler serves a random (or, if daily is true, a reproducible
+// verse-of-the-day) pick from b. The seed behind the pick is resolved by
+// rngFor from ?seed=, daily, and ?narrow=, and surfaced back to the caller
+// via VerseResult.Seed so the request can be replayed.
+func randomHandler(b *Bible, w http.ResponseWriter, r *http.Request, daily bool) {
 	narrow := r.URL.Query().Get("narrow")
 	if narrow != "" {
-		if _, ok := catMap[narrow]; !ok {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			fmt.Fprintf(w, `<!DOCTYPE html>
-<html lang="%s">
-<head>
-  <meta charset="UTF-8">
-  <title>%s</title>
-  <style>
-    body { font-family: sans-serif; background: #fff8f0; color: #333; padding: 2rem; }
-    h1 { color: #c0392b; }
-    ul { margin-top: 1em; }
-    li { margin: 0.5em 0; }
-    code { background: #eee; padding: 0.2em 0.4em; }
-  </style>
-</head>
-<body>
-  <h1>`+trans.InvalidParamMessage+`</h1>
-  <p>`+trans.AcceptedValuesMessage+`</p>
-  <ul>`, trans.LanguageCode, trans.InvalidParamTitle, html.EscapeString(narrow))
-
-			for _, c := range categories {
-				fmt.Fprintf(w,
-					`<li><code>%s</code> — %s</li>`+"\n",
-					html.EscapeString(c.Key),
-					html.EscapeString(catLabel[c.Key]),
-				)
-			}
-
-			fmt.Fprint(w, `
-  </ul>
-</body>
-</html>`)
+		if _, ok := b.catMap[narrow]; !ok {
+			writeInvalidNarrow(b, w, r, narrow)
 			return
 		}
 	}
 
-	pool := books
+	explicitSeed := r.URL.Query().Get("seed")
+	rng, seed := rngFor(explicitSeed, daily, narrow)
+	cacheControl := "no-store"
+	if explicitSeed != "" || daily {
+		cacheControl = "public, max-age=3600"
+	}
+
+	pool := b.books
 	if narrow != "" {
-		r := catMap[narrow]
-		pool = sliceRange(r.LowID, r.HighID)
+		c := b.catMap[narrow]
+		pool = b.sliceRange(c.LowID, c.HighID)
 	}
 
 	total := 0
-	for _, b := range pool {
-		total += b.LineCount
+	for _, bk := range pool {
+		total += bk.LineCount
 	}
 	if total == 0 {
-		http.Error(w, trans.NoVersesError, http.StatusBadRequest)
+		http.Error(w, b.NoVersesError, http.StatusBadRequest)
 		return
 	}
 
@@ -220,37 +93,63 @@ func randomHandler(w http.ResponseWriter, r *http.Request) {
 	cum := 0
 	var sel BookInfo
 	var offset int
-	for _, b := range pool {
-		if choice <= cum+b.LineCount {
-			sel = b
+	for _, bk := range pool {
+		if choice <= cum+bk.LineCount {
+			sel = bk
 			offset = choice - cum
 			break
 		}
-		cum += b.LineCount
+		cum += bk.LineCount
 	}
 
-	f, err := bibleFS.Open("randfromkjv/" + sel.File)
+	scanner, err := b.vr.lineScanner(sel, offset)
 	if err != nil {
-		http.Error(w, trans.BookNotFoundError, http.StatusNotFound)
+		http.Error(w, b.DecompressionError, http.StatusInternalServerError)
 		return
 	}
-	defer f.Close()
 
-	gz, err := gzip.NewReader(f)
-	if err != nil {
-		http.Error(w, trans.DecompressionError, http.StatusInternalServerError)
-		return
+	var verses []VerseEntry
+	for scanner.Scan() {
+		verses = append(verses, splitVerseEntry(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("scan error in %s: %v", sel.File, err)
 	}
-	defer gz.Close()
 
-	scanner := bufio.NewScanner(gz)
-	for i := 1; i < offset; i++ {
-		if !scanner.Scan() {
-			break
+	writeVerseResult(w, r, VerseResult{
+		Title:      fmt.Sprintf(b.VersePageTitleFormat, sel.Name, offset, sel.LineCount),
+		Book:       sel.Name,
+		BookID:     sel.ID,
+		Language:   b.LanguageCode,
+		LineNumber: offset,
+		LineCount:  sel.LineCount,
+		Verses:     verses,
+		Category:   narrow,
+		Seed:       seed,
+	}, cacheControl)
+}
+
+// splitVerseEntry splits a "chapter:verse text" line into a VerseEntry.
+func splitVerseEntry(line string) VerseEntry {
+	if num, text, ok := strings.Cut(line, " "); ok {
+		return VerseEntry{Number: num, Text: text}
+	}
+	return VerseEntry{Text: line}
+}
+
+// writeInvalidNarrow reports an unrecognized ?narrow= value, rendering the
+// same category listing page as before for HTML clients and a plain-text
+// error for JSON/text clients.
+func writeInvalidNarrow(b *Bible, w http.ResponseWriter, r *http.Request, narrow string) {
+	if _, html := rendererFor(r).(htmlVerseRenderer); !html {
+		msg := fmt.Sprintf(b.InvalidParamMessage, narrow) + "\n" + b.AcceptedValuesMessage
+		for _, c := range categories {
+			msg += fmt.Sprintf("\n  %s — %s", c.Key, b.catLabel[c.Key])
 		}
+		http.Error(w, msg, http.StatusBadRequest)
+		return
 	}
 
-	title := fmt.Sprintf(trans.VersePageTitleFormat, sel.Name, offset, sel.LineCount)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(w, `<!DOCTYPE html>
 <html lang="%s">
@@ -258,40 +157,197 @@ func randomHandler(w http.ResponseWriter, r *http.Request) {
   <meta charset="UTF-8">
   <title>%s</title>
   <style>
-    body { background: #fafafa; color: #333; font-family: sans-serif; padding: 1rem; line-height: 1.6; }
-    .verse-num { color: #4caf50; font-weight: bold; }
-    .verses p { margin: 0.5em 0; }
+    body { font-family: sans-serif; background: #fff8f0; color: #333; padding: 2rem; }
+    h1 { color: #c0392b; }
+    ul { margin-top: 1em; }
+    li { margin: 0.5em 0; }
+    code { background: #eee; padding: 0.2em 0.4em; }
   </style>
 </head>
 <body>
-  <h1>%s</h1>
-  <div class="verses">`, trans.LanguageCode, html.EscapeString(title), html.EscapeString(title))
+  <h1>`+b.InvalidParamMessage+`</h1>
+  <p>`+b.AcceptedValuesMessage+`</p>
+  <ul>`, b.LanguageCode, b.InvalidParamTitle, html.EscapeString(narrow))
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) == 2 {
-			fmt.Fprintf(w,
-				`<p><span class="verse-num">%s</span> %s</p>`+"\n",
-				html.EscapeString(parts[0]),
-				parts[1],
-			)
-		} else {
-			fmt.Fprintf(w, `<p>%s</p>`+"\n", html.EscapeString(line))
+	for _, c := range categories {
+		fmt.Fprintf(w,
+			`<li><code>%s</code> — %s</li>`+"\n",
+			html.EscapeString(c.Key),
+			html.EscapeString(b.catLabel[c.Key]),
+		)
+	}
+
+	fmt.Fprint(w, `
+  </ul>
+</body>
+</html>`)
+}
+
+// refHandler serves GET /ref/{book}/{chapter}[:{verse}[-{verse}]], e.g.
+// /ref/John/3:16 or /ref/1%20Cor/13:4-7. A slash in place of the final
+// colon is also accepted, e.g. /ref/John/3/16.
+func refHandler(b *Bible, w http.ResponseWriter, r *http.Request) {
+	serveRef(b, w, r, strings.TrimPrefix(r.URL.Path, "/ref/"))
+}
+
+// serveRef looks up and renders the "{book}/{chapter}[:{verse}[-{verse}]]"
+// path described by path, which has already had any /ref/ or /t/{code}/ref/
+// prefix stripped. A trailing "/{verse}" is treated the same as ":{verse}",
+// so both /ref/John/3:16 and /ref/John/3/16 resolve the same reference.
+func serveRef(b *Bible, w http.ResponseWriter, r *http.Request, path string) {
+	segs := strings.SplitN(path, "/", 2)
+	if len(segs) != 2 {
+		http.Error(w, b.InvalidParamMessage, http.StatusBadRequest)
+		return
+	}
+	bookSeg, err := url.PathUnescape(segs[0])
+	if err != nil {
+		http.Error(w, b.InvalidParamMessage, http.StatusBadRequest)
+		return
+	}
+	chapterSeg, err := url.PathUnescape(segs[1])
+	if err != nil {
+		http.Error(w, b.InvalidParamMessage, http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(chapterSeg, ":") {
+		if i := strings.LastIndex(chapterSeg, "/"); i != -1 {
+			chapterSeg = chapterSeg[:i] + ":" + chapterSeg[i+1:]
 		}
 	}
 
-	fmt.Fprint(w, "</div></body></html>")
-	if err := scanner.Err(); err != nil {
-		log.Printf("scan error in %s: %v", sel.File, err)
+	ref, err := b.parseReference(bookSeg + " " + chapterSeg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines, firstLine, err := b.locateVerses(ref)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	verses := make([]VerseEntry, len(lines))
+	for i, line := range lines {
+		verses[i] = splitVerseEntry(line)
+	}
+
+	writeVerseResult(w, r, VerseResult{
+		Title:      fmt.Sprintf(b.VersePageTitleFormat, ref.Book.Name, firstLine, ref.Book.LineCount),
+		Book:       ref.Book.Name,
+		BookID:     ref.Book.ID,
+		Language:   b.LanguageCode,
+		LineNumber: firstLine,
+		LineCount:  ref.Book.LineCount,
+		Verses:     verses,
+	}, "public, max-age=3600")
+}
+
+// searchHandler serves GET /search?q=...&narrow=..., rendering the
+// matching verses in the format negotiated for r.
+func searchHandler(b *Bible, w http.ResponseWriter, r *http.Request) {
+	narrow := r.URL.Query().Get("narrow")
+	if narrow != "" {
+		if _, ok := b.catMap[narrow]; !ok {
+			writeInvalidNarrow(b, w, r, narrow)
+			return
+		}
+	}
+
+	query := r.URL.Query().Get("q")
+	hits := b.Search(query, SearchOptions{Narrow: narrow})
+	writeSearchResults(w, r, query, hits)
+}
+
+// translationHandler serves /t/{code}/random and /t/{code}/ref/..., the
+// path-based counterpart to the ?t= query parameter on / and /ref/.
+func translationHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/t/")
+	code, rest, ok := strings.Cut(path, "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	b, ok := bibles[code]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown translation %q", code), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case rest == "random":
+		randomHandler(b, w, r, false)
+	case rest == "daily":
+		randomHandler(b, w, r, true)
+	case strings.HasPrefix(rest, "ref/"):
+		serveRef(b, w, r, strings.TrimPrefix(rest, "ref/"))
+	case rest == "search":
+		searchHandler(b, w, r)
+	default:
+		http.NotFound(w, r)
 	}
 }
 
+// corpusDirs collects repeated -corpus flags naming additional translation
+// directories to load alongside the embedded KJV.
+type corpusDirs []string
+
+func (c *corpusDirs) String() string { return strings.Join(*c, ",") }
+func (c *corpusDirs) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
 func main() {
 	port := flag.Int("p", 1616, "port to listen on")
+	var corpora corpusDirs
+	flag.Var(&corpora, "corpus", "path to an additional translation directory (containing index.txt, *.txt.gz, *.idx); may be repeated")
 	flag.Parse()
 
-	http.HandleFunc("/", randomHandler)
+	for _, dir := range corpora {
+		b, err := LoadBible(os.DirFS(dir))
+		if err != nil {
+			log.Fatalf("loading corpus %s: %v", dir, err)
+		}
+		bibles[b.LanguageCode] = b
+		log.Printf("loaded translation %q from %s", b.LanguageCode, dir)
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bibleFor(r.URL.Query().Get("t"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		randomHandler(b, w, r, false)
+	})
+	http.HandleFunc("/daily", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bibleFor(r.URL.Query().Get("t"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		randomHandler(b, w, r, true)
+	})
+	http.HandleFunc("/ref/", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bibleFor(r.URL.Query().Get("t"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		refHandler(b, w, r)
+	})
+	http.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		b, ok := bibleFor(r.URL.Query().Get("t"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		searchHandler(b, w, r)
+	})
+	http.HandleFunc("/t/", translationHandler)
+
 	addr := fmt.Sprintf(":%d", *port)
 	log.Printf("listening on http://localhost%s/?narrow=nt", addr)
 	log.Fatal(http.ListenAndServe(addr, nil))
@@ -0,0 +1,141 @@
+// Command indexgen builds the sibling .idx files that verseReader uses for
+// random access into the embedded *.txt.gz books.
+//
+// For every N.txt.gz in the current directory it records the uncompressed
+// byte offset of each verse line, then re-packs the book's gzip stream with
+// a sync flush every accessPointInterval bytes of uncompressed input. Each
+// flush point is byte-aligned, so decompression can resume there given the
+// preceding dictWindow bytes of uncompressed output as a flate dictionary
+// (the deflate format allows at most a 32 KiB window). The resulting
+// checkpoint list, paired with the line offsets, lets a reader seek to
+// roughly the right spot in the compressed stream instead of decompressing
+// a book from the start.
+//
+// Run via `go generate ./...` from the randfromkjv directory whenever a
+// *.txt.gz book is added or changed.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	accessPointInterval = 32 * 1024
+	dictWindow          = 32 * 1024 // max flate dictionary size
+	gzipHeaderLen       = 10        // ID1 ID2 CM FLG MTIME(4) XFL OS; no extra/name/comment fields are set
+)
+
+// verseCheckpoint is a resumable point in a book's recompressed gzip
+// stream. CompressedOffset is relative to the end of the fixed-size gzip
+// header, since resuming decompression means feeding raw deflate data to
+// flate.NewReaderDict rather than reading gzip framing from the start.
+type verseCheckpoint struct {
+	UncompressedOffset int64
+	CompressedOffset   int64
+	Dictionary         []byte
+}
+
+// bookIndex is gob-encoded to N.idx alongside N.txt.gz.
+type bookIndex struct {
+	LineOffsets []int64
+	Checkpoints []verseCheckpoint
+}
+
+func main() {
+	matches, err := filepath.Glob("*.txt.gz")
+	if err != nil {
+		log.Fatalf("glob *.txt.gz: %v", err)
+	}
+	for _, path := range matches {
+		if err := indexBook(path); err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		fmt.Println("indexed", path)
+	}
+}
+
+func indexBook(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("opening gzip stream: %w", err)
+	}
+	content, err := io.ReadAll(gz)
+	if err != nil {
+		return fmt.Errorf("decompressing: %w", err)
+	}
+	gz.Close()
+
+	idx := bookIndex{LineOffsets: lineOffsets(content)}
+
+	var out bytes.Buffer
+	w, err := gzip.NewWriterLevel(&out, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	// Force the gzip header out now so the first checkpoint's
+	// CompressedOffset is measured from the start of the deflate stream.
+	if _, err := w.Write(nil); err != nil {
+		return err
+	}
+
+	for offset := 0; offset < len(content); offset += accessPointInterval {
+		end := offset + accessPointInterval
+		if end > len(content) {
+			end = len(content)
+		}
+		dictStart := offset - dictWindow
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		idx.Checkpoints = append(idx.Checkpoints, verseCheckpoint{
+			UncompressedOffset: int64(offset),
+			CompressedOffset:   int64(out.Len()) - gzipHeaderLen,
+			Dictionary:         append([]byte(nil), content[dictStart:offset]...),
+		})
+		if _, err := w.Write(content[offset:end]); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	idxPath := strings.TrimSuffix(path, ".txt.gz") + ".idx"
+	f, err := os.Create(idxPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(idx)
+}
+
+// lineOffsets returns the byte offset, within content, of the start of
+// every line (content[0:] for line 1, then the byte after every '\n').
+func lineOffsets(content []byte) []int64 {
+	offsets := []int64{0}
+	for i, b := range content {
+		if b == '\n' && i+1 < len(content) {
+			offsets = append(offsets, int64(i+1))
+		}
+	}
+	return offsets
+}
@@ -2,155 +2,48 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"compress/gzip"
 	"embed"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"strconv"
+	"regexp"
 	"strings"
-	"time"
-
-	"gopkg.in/yaml.v2"
 )
 
+//go:generate go run ./indexgen
+//go:generate go run ./searchgen
+
 //go:embed index.txt
 //go:embed *.txt.gz
-var bibleFS embed.FS
-
-// Translations holds all user‐facing messages from the index frontmatter.
-type Translations struct {
-	LanguageCode          string `yaml:"language"`
-	InvalidParamMessage   string `yaml:"invalidParamMessage"`
-	AcceptedValuesMessage string `yaml:"acceptedValuesMessage"`
-	NoVersesError         string `yaml:"noVersesError"`
-}
-
-var trans Translations
-
-// BookInfo holds metadata for each embedded book.
-type BookInfo struct {
-	ID        int
-	Name      string
-	LineCount int
-	File      string
-}
+//go:embed *.idx
+//go:embed *.gob
+var embeddedKJV embed.FS
 
 var (
-	books      []BookInfo
-	rng        *rand.Rand
-	categories []struct {
-		Key           string
-		LowID, HighID int
-	}
-	catMap   map[string]struct{ LowID, HighID int }
-	catLabel map[string]string
+	// bibles holds every loaded translation, keyed by its frontmatter
+	// language code. defaultCode selects which one -translation falls
+	// back to when unset.
+	bibles      map[string]*Bible
+	defaultCode string
 )
 
 func init() {
-	rng = rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	raw, err := bibleFS.ReadFile("index.txt")
+	kjv, err := LoadBible(embeddedKJV)
 	if err != nil {
-		log.Fatalf("cannot read index.txt: %v", err)
-	}
-
-	// parse YAML frontmatter
-	var content []byte
-	if bytes.HasPrefix(raw, []byte("---\n")) {
-		parts := bytes.SplitN(raw, []byte("\n---\n"), 2)
-		if err := yaml.Unmarshal(parts[0], &trans); err != nil {
-			log.Fatalf("failed to parse translations: %v", err)
-		}
-		content = parts[1]
-	} else {
-		content = raw
-	}
-
-	// build books[]
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	for scanner.Scan() {
-		parts := strings.Split(scanner.Text(), "|")
-		if len(parts) != 3 {
-			continue
-		}
-		id, err := strconv.Atoi(parts[0])
-		if err != nil {
-			continue
-		}
-		cnt, err := strconv.Atoi(parts[2])
-		if err != nil {
-			continue
-		}
-		books = append(books, BookInfo{
-			ID:        id,
-			Name:      parts[1],
-			LineCount: cnt,
-			File:      fmt.Sprintf("%d.txt.gz", id),
-		})
-	}
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("error reading index content: %v", err)
-	}
-
-	// named categories
-	categories = []struct {
-		Key           string
-		LowID, HighID int
-	}{
-		{"ot", 10, 460},
-		{"nt", 470, 730},
-		{"pentateuch", 10, 50},
-		{"historical", 60, 190},
-		{"poetic", 220, 260},
-		{"major", 290, 340},
-		{"minor", 350, 460},
-		{"gospels", 470, 500},
-		{"apostolic", 510, 720},
-		{"acts", 510, 510},
-		{"paul", 520, 650},
-		{"general", 660, 720},
-		{"revelation", 730, 730},
-	}
-
-	catMap = make(map[string]struct{ LowID, HighID int }, len(categories))
-	catLabel = make(map[string]string, len(categories))
-	for _, c := range categories {
-		catMap[c.Key] = struct{ LowID, HighID int }{c.LowID, c.HighID}
-		slice := sliceRange(c.LowID, c.HighID)
-		if len(slice) == 0 {
-			catLabel[c.Key] = ""
-		} else if c.LowID == c.HighID {
-			catLabel[c.Key] = slice[0].Name
-		} else {
-			first := slice[0].Name
-			last := slice[len(slice)-1].Name
-			catLabel[c.Key] = first + " — " + last
-		}
+		log.Fatalf("cannot load embedded KJV: %v", err)
 	}
+	bibles = map[string]*Bible{kjv.LanguageCode: kjv}
+	defaultCode = kjv.LanguageCode
 }
 
-// sliceRange returns books whose IDs ∈ [lowID…highID].
-func sliceRange(lowID, highID int) []BookInfo {
-	start, end := -1, -1
-	for i, b := range books {
-		if start < 0 && b.ID >= lowID {
-			start = i
-		}
-		if b.ID <= highID {
-			end = i
-		}
-		if b.ID > highID {
-			break
-		}
-	}
-	if start >= 0 && end >= start {
-		return books[start : end+1]
-	}
+// corpusDirs collects repeated -corpus flags naming additional translation
+// directories to load alongside the embedded KJV.
+type corpusDirs []string
+
+func (c *corpusDirs) String() string { return strings.Join(*c, ",") }
+func (c *corpusDirs) Set(v string) error {
+	*c = append(*c, v)
 	return nil
 }
 
@@ -158,8 +51,33 @@ func main() {
 	// CLI flags
 	narrow := flag.String("narrow", "", "category to narrow (e.g. ot, nt, gospels)")
 	colorize := flag.Bool("c", false, "highlight numbers in soft green")
+	ref := flag.String("ref", "", `fetch a specific passage instead of a random verse, e.g. "John 3:16" or "Ps 23"`)
+	search := flag.String("search", "", `search for verses instead of picking one, e.g. "faith hope love" or "faith OR hope" or "\"have not charity\""`)
+	seed := flag.String("seed", "", "reproduce a specific pick instead of a fresh random one (a number, or any phrase to hash into a seed)")
+	daily := flag.Bool("daily", false, "pick today's verse of the day (same pick for everyone, worldwide, until UTC midnight; salted with -narrow if set)")
+	translation := flag.String("translation", "", "translation code to use (defaults to the embedded KJV)")
+	var corpora corpusDirs
+	flag.Var(&corpora, "corpus", "path to an additional translation directory (containing index.txt, *.txt.gz, *.idx); may be repeated")
 	flag.Parse()
 
+	for _, dir := range corpora {
+		b, err := LoadBible(os.DirFS(dir))
+		if err != nil {
+			log.Fatalf("loading corpus %s: %v", dir, err)
+		}
+		bibles[b.LanguageCode] = b
+	}
+
+	code := *translation
+	if code == "" {
+		code = defaultCode
+	}
+	b, ok := bibles[code]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown translation %q\n", code)
+		os.Exit(1)
+	}
+
 	// prepare ANSI color codes
 	prefix, suffix := "", ""
 	if *colorize {
@@ -167,96 +85,149 @@ func main() {
 		suffix = "\033[0m"  // reset
 	}
 
+	if *ref != "" {
+		printReference(b, *ref, prefix, suffix)
+		return
+	}
+
 	// validate narrow
 	if *narrow != "" {
-		if _, ok := catMap[*narrow]; !ok {
-			fmt.Fprintf(os.Stderr, trans.InvalidParamMessage+"\n", *narrow)
-			fmt.Fprintln(os.Stderr, trans.AcceptedValuesMessage)
+		if _, ok := b.catMap[*narrow]; !ok {
+			fmt.Fprintf(os.Stderr, b.InvalidParamMessage+"\n", *narrow)
+			fmt.Fprintln(os.Stderr, b.AcceptedValuesMessage)
 			for _, c := range categories {
-				fmt.Fprintf(os.Stderr, "  %s — %s\n", c.Key, catLabel[c.Key])
+				fmt.Fprintf(os.Stderr, "  %s — %s\n", c.Key, b.catLabel[c.Key])
 			}
 			os.Exit(1)
 		}
 	}
 
+	if *search != "" {
+		hits := b.Search(*search, SearchOptions{Narrow: *narrow})
+		printSearchResults(hits, prefix, suffix)
+		return
+	}
+
 	// build selection pool
-	pool := books
+	pool := b.books
 	if *narrow != "" {
-		r := catMap[*narrow]
-		pool = sliceRange(r.LowID, r.HighID)
+		r := b.catMap[*narrow]
+		pool = b.sliceRange(r.LowID, r.HighID)
 	}
 
 	// total lines
 	total := 0
-	for _, b := range pool {
-		total += b.LineCount
+	for _, bk := range pool {
+		total += bk.LineCount
 	}
 	if total == 0 {
-		fmt.Fprintln(os.Stderr, trans.NoVersesError)
+		fmt.Fprintln(os.Stderr, b.NoVersesError)
 		os.Exit(1)
 	}
 
-	// pick a random global line
+	// pick a global line, reproducibly if -seed or -daily was given
+	rng, resolvedSeed := rngFor(*seed, *daily, *narrow)
 	choice := rng.Intn(total) + 1
 
 	// locate book + offset
 	cum := 0
 	var sel BookInfo
 	var offset int
-	for _, b := range pool {
-		if choice <= cum+b.LineCount {
-			sel = b
+	for _, bk := range pool {
+		if choice <= cum+bk.LineCount {
+			sel = bk
 			offset = choice - cum
 			break
 		}
-		cum += b.LineCount
-	}
-
-	// open & decompress
-	f, err := bibleFS.Open(sel.File)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, trans.NoVersesError)
-		os.Exit(1)
+		cum += bk.LineCount
 	}
-	defer f.Close()
 
-	gz, err := gzip.NewReader(f)
+	// locate the chosen verse
+	scanner, err := b.vr.lineScanner(sel, offset)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, trans.NoVersesError)
+		fmt.Fprintln(os.Stderr, b.NoVersesError)
 		os.Exit(1)
 	}
-	defer gz.Close()
-
-	// skip to chosen verse
-	scanner := bufio.NewScanner(gz)
-	for i := 1; i < offset; i++ {
-		if !scanner.Scan() {
-			break
-		}
-	}
 
 	// header with highlighted numbers
-	fmt.Printf("%s (line %s%d%s/%s%d%s)\n\n",
+	fmt.Printf("%s (line %s%d%s/%s%d%s, seed %s%d%s)\n\n",
 		sel.Name,
 		prefix, offset, suffix,
 		prefix, sel.LineCount, suffix,
+		prefix, resolvedSeed, suffix,
 	)
 
 	// verses with highlighted verse numbers
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) == 2 {
-			fmt.Printf("%s%s%s %s\n",
-				prefix, parts[0], suffix,
-				parts[1],
-			)
-		} else {
-			fmt.Println(line)
-		}
+		printVerseLine(scanner.Text(), prefix, suffix)
 	}
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintln(os.Stderr, "error reading verses:", err)
 		os.Exit(1)
 	}
 }
+
+// printVerseLine prints a single "chapter:verse text" line with its
+// leading number optionally wrapped in ANSI color codes.
+func printVerseLine(line, prefix, suffix string) {
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) == 2 {
+		fmt.Printf("%s%s%s %s\n", prefix, parts[0], suffix, parts[1])
+	} else {
+		fmt.Println(line)
+	}
+}
+
+// printReference looks up and prints a specific citation such as
+// "John 3:16" or "Ps 23" from b, exiting the process on failure.
+func printReference(b *Bible, query, prefix, suffix string) {
+	ref, err := b.parseReference(query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	lines, firstLine, err := b.locateVerses(ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	lastLine := firstLine + len(lines) - 1
+
+	fmt.Printf("%s (line %s%d%s-%s%d%s/%s%d%s)\n\n",
+		ref.Book.Name,
+		prefix, firstLine, suffix,
+		prefix, lastLine, suffix,
+		prefix, ref.Book.LineCount, suffix,
+	)
+	for _, line := range lines {
+		printVerseLine(line, prefix, suffix)
+	}
+}
+
+// printSearchResults prints each search hit as a "Book chapter:verse"
+// header followed by its text, with the matched terms highlighted the
+// same way printVerseLine highlights a verse number.
+func printSearchResults(hits []Hit, prefix, suffix string) {
+	fmt.Printf("%d result(s)\n\n", len(hits))
+	for _, h := range hits {
+		fmt.Printf("%s %s%s%s\n", h.Book, prefix, h.Number, suffix)
+		fmt.Println(highlightText(h.Text, h.Terms, prefix, suffix))
+	}
+}
+
+// highlightText wraps each case-insensitive occurrence of any of terms in
+// text with prefix/suffix (the same ANSI color codes used elsewhere).
+func highlightText(text string, terms []string, prefix, suffix string) string {
+	if len(terms) == 0 {
+		return text
+	}
+	pattern := make([]string, len(terms))
+	for i, t := range terms {
+		pattern[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile(`(?i)(` + strings.Join(pattern, "|") + `)`)
+	return re.ReplaceAllStringFunc(text, func(m string) string {
+		return prefix + m + suffix
+	})
+}
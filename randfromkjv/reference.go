@@ -0,0 +1,228 @@
+// reference.go
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// refBookAbbrev maps common English book abbreviations and alternate
+// spellings (normalized: lowercased, punctuation and spaces stripped) to
+// the canonical book name used in index.txt.
+var refBookAbbrev = map[string]string{
+	"gen": "Genesis", "ge": "Genesis", "gn": "Genesis",
+	"exo": "Exodus", "ex": "Exodus", "exod": "Exodus",
+	"lev": "Leviticus", "le": "Leviticus", "lv": "Leviticus",
+	"num": "Numbers", "nu": "Numbers", "nm": "Numbers", "nb": "Numbers",
+	"deu": "Deuteronomy", "dt": "Deuteronomy", "deut": "Deuteronomy",
+	"jos": "Joshua", "josh": "Joshua",
+	"jdg": "Judges", "judg": "Judges",
+	"rut": "Ruth",
+	"1sa": "1 Samuel", "1sam": "1 Samuel", "1st": "1 Samuel",
+	"2sa": "2 Samuel", "2sam": "2 Samuel",
+	"1ki": "1 Kings", "1kgs": "1 Kings",
+	"2ki": "2 Kings", "2kgs": "2 Kings",
+	"1ch": "1 Chronicles", "1chr": "1 Chronicles",
+	"2ch": "2 Chronicles", "2chr": "2 Chronicles",
+	"ezr": "Ezra",
+	"neh": "Nehemiah",
+	"est": "Esther",
+	"job": "Job",
+	"psa": "Psalms", "ps": "Psalms", "psalm": "Psalms",
+	"pro": "Proverbs", "prov": "Proverbs",
+	"ecc": "Ecclesiastes", "eccl": "Ecclesiastes",
+	"sos": "Song of Solomon", "song": "Song of Solomon", "canticles": "Song of Solomon",
+	"isa": "Isaiah",
+	"jer": "Jeremiah",
+	"lam": "Lamentations",
+	"eze": "Ezekiel", "ezek": "Ezekiel",
+	"dan": "Daniel",
+	"hos": "Hosea",
+	"joe": "Joel", "joel": "Joel",
+	"amo": "Amos",
+	"oba": "Obadiah", "obad": "Obadiah",
+	"jon": "Jonah",
+	"mic": "Micah",
+	"nah": "Nahum",
+	"hab": "Habakkuk",
+	"zep": "Zephaniah", "zeph": "Zephaniah",
+	"hag": "Haggai",
+	"zec": "Zechariah", "zech": "Zechariah",
+	"mal": "Malachi",
+	"mat": "Matthew", "matt": "Matthew", "mt": "Matthew",
+	"mar": "Mark", "mk": "Mark", "mrk": "Mark",
+	"luk": "Luke", "lk": "Luke",
+	"joh": "John", "jhn": "John", "jn": "John",
+	"act": "Acts", "ac": "Acts",
+	"rom": "Romans", "ro": "Romans",
+	"1co": "1 Corinthians", "1cor": "1 Corinthians",
+	"2co": "2 Corinthians", "2cor": "2 Corinthians",
+	"gal": "Galatians",
+	"eph": "Ephesians",
+	"phi": "Philippians", "phil": "Philippians", "php": "Philippians",
+	"col": "Colossians",
+	"1th": "1 Thessalonians", "1thess": "1 Thessalonians",
+	"2th": "2 Thessalonians", "2thess": "2 Thessalonians",
+	"1ti": "1 Timothy", "1tim": "1 Timothy",
+	"2ti": "2 Timothy", "2tim": "2 Timothy",
+	"tit": "Titus",
+	"phm": "Philemon", "phlm": "Philemon",
+	"heb": "Hebrews",
+	"jam": "James", "jas": "James",
+	"1pe": "1 Peter", "1pet": "1 Peter",
+	"2pe": "2 Peter", "2pet": "2 Peter",
+	"1jo": "1 John", "1jn": "1 John",
+	"2jo": "2 John", "2jn": "2 John",
+	"3jo": "3 John", "3jn": "3 John",
+	"jud": "Jude",
+	"rev": "Revelation", "re": "Revelation", "revelation": "Revelation",
+}
+
+// reference identifies a book, a chapter, and an optional inclusive verse
+// range within it. VerseStart of 0 means "whole chapter".
+type reference struct {
+	Book       BookInfo
+	Chapter    int
+	VerseStart int
+	VerseEnd   int
+}
+
+// refSpec splits a citation's chapter[:verse[-verse]] half into its parts,
+// e.g. "3:16" -> (3, 16, 16), "13:4-7" -> (13, 4, 7), "23" -> (23, 0, 0).
+func refSpec(spec string) (chapter, verseStart, verseEnd int, err error) {
+	chapterPart, versePart, hasVerse := strings.Cut(spec, ":")
+	chapter, err = strconv.Atoi(strings.TrimSpace(chapterPart))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid chapter %q", chapterPart)
+	}
+	if !hasVerse {
+		return chapter, 0, 0, nil
+	}
+	startPart, endPart, hasRange := strings.Cut(versePart, "-")
+	verseStart, err = strconv.Atoi(strings.TrimSpace(startPart))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid verse %q", startPart)
+	}
+	verseEnd = verseStart
+	if hasRange {
+		verseEnd, err = strconv.Atoi(strings.TrimSpace(endPart))
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid verse %q", endPart)
+		}
+	}
+	return chapter, verseStart, verseEnd, nil
+}
+
+// parseReference parses a citation such as "John 3:16", "1 Cor 13:4-7", or
+// "Ps 23" into a reference, resolving the book name against b's books
+// (which includes its localized names loaded from index.txt) and the
+// common abbreviations in refBookAbbrev.
+func (b *Bible) parseReference(q string) (*reference, error) {
+	q = strings.TrimSpace(q)
+	sep := strings.LastIndexAny(q, " \t")
+	if sep < 0 {
+		return nil, fmt.Errorf("could not parse reference %q", q)
+	}
+	bookPart, spec := q[:sep], strings.TrimSpace(q[sep+1:])
+
+	book, err := b.resolveBook(bookPart)
+	if err != nil {
+		return nil, err
+	}
+	chapter, verseStart, verseEnd, err := refSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &reference{Book: *book, Chapter: chapter, VerseStart: verseStart, VerseEnd: verseEnd}, nil
+}
+
+// resolveBook matches name against b's loaded book list, trying an exact
+// (case-insensitive) match first and then the abbreviation table.
+func (b *Bible) resolveBook(name string) (*BookInfo, error) {
+	name = strings.TrimSpace(name)
+	for i, bk := range b.books {
+		if strings.EqualFold(bk.Name, name) {
+			return &b.books[i], nil
+		}
+	}
+	if canon, ok := refBookAbbrev[normalizeBookName(name)]; ok {
+		for i, bk := range b.books {
+			if strings.EqualFold(bk.Name, canon) {
+				return &b.books[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown book %q", name)
+}
+
+// normalizeBookName lowercases name and strips spaces and periods so
+// "1 Cor.", "1Cor", and "1 cor" all hash to the same abbreviation key.
+func normalizeBookName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, ".", "")
+	name = strings.ReplaceAll(name, " ", "")
+	return name
+}
+
+// splitVerseNum parses a "chapter:verse" line prefix like the one this
+// corpus embeds at the start of every verse line.
+func splitVerseNum(s string) (chapter, verse int, ok bool) {
+	c, v, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, false
+	}
+	chapter, err1 := strconv.Atoi(c)
+	verse, err2 := strconv.Atoi(v)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return chapter, verse, true
+}
+
+// locateVerses returns the consecutive lines of ref.Book matching
+// ref.Chapter (and, if ref.VerseStart is set, the inclusive verse range),
+// along with the 1-indexed line number of the first line returned.
+func (b *Bible) locateVerses(ref *reference) ([]string, int, error) {
+	scanner, err := b.vr.lineScanner(ref.Book, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []string
+	firstLine, lineNo := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		chapter, verse, ok := splitVerseNum(parts[0])
+		if !ok || chapter < ref.Chapter {
+			continue
+		}
+		if chapter > ref.Chapter {
+			break
+		}
+		if ref.VerseStart != 0 {
+			if verse < ref.VerseStart {
+				continue
+			}
+			if verse > ref.VerseEnd {
+				break
+			}
+		}
+		if firstLine == 0 {
+			firstLine = lineNo
+		}
+		matched = append(matched, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(matched) == 0 {
+		return nil, 0, fmt.Errorf("no verses found for %s %d", ref.Book.Name, ref.Chapter)
+	}
+	return matched, firstLine, nil
+}
@@ -0,0 +1,300 @@
+// search.go
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// posting is one occurrence of a token: the book it was found in and the
+// 1-indexed line within that book. Postings are what searchgen gob-encodes
+// into each corpus's search.gob.
+type posting struct {
+	BookID int
+	Line   int
+}
+
+// searchIndex is the inverted index loaded from search.gob: normalized
+// token -> the postings it occurs in.
+type searchIndex struct {
+	Postings map[string][]posting
+}
+
+// Hit is one verse line matching a Search query.
+type Hit struct {
+	Book   string `json:"book"`
+	BookID int    `json:"bookId"`
+	Line   int    `json:"-"`
+	Number string `json:"number"`
+	Text   string `json:"text"`
+	// Terms holds the surface forms (as typed in the query) that matched
+	// this hit, for callers that want to highlight them.
+	Terms []string `json:"terms"`
+}
+
+// SearchOptions narrows a Search call the same way ?narrow= narrows a
+// random pick: by a named category from catMap/sliceRange.
+type SearchOptions struct {
+	Narrow string
+}
+
+// term is one unit of a parsed query: either a single stemmed word or a
+// quoted phrase (several words that must appear, in order, in the verse).
+type term struct {
+	words  []string
+	phrase bool
+	raw    string
+}
+
+// Search finds verses matching query against b's inverted index. query
+// supports AND (space-separated terms, all must match), OR ("faith OR
+// hope", either side may match), and phrase ("\"have not charity\"")
+// queries, combined by splitting on " OR " into groups that are ANDed
+// internally and ORed against each other. Results are sorted in
+// (bookID, line) order. Returns nil if b has no search index loaded or
+// query is empty.
+func (b *Bible) Search(query string, opts SearchOptions) []Hit {
+	if b.searchIdx == nil || strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	var allow map[int]bool
+	if opts.Narrow != "" {
+		if cat, ok := b.catMap[opts.Narrow]; ok {
+			allow = make(map[int]bool)
+			for _, bk := range b.sliceRange(cat.LowID, cat.HighID) {
+				allow[bk.ID] = true
+			}
+		}
+	}
+
+	matched := make(map[posting]map[string]bool)
+	for _, group := range parseQuery(query) {
+		if len(group) == 0 {
+			continue
+		}
+		candidates := b.postingsForTerm(group[0])
+		for _, t := range group[1:] {
+			candidates = intersectPostings(candidates, b.postingsForTerm(t))
+		}
+		for p := range candidates {
+			if allow != nil && !allow[p.BookID] {
+				continue
+			}
+			if matched[p] == nil {
+				matched[p] = make(map[string]bool)
+			}
+			for _, t := range group {
+				matched[p][t.raw] = true
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(matched))
+	for p, terms := range matched {
+		bk, ok := b.bookByID(p.BookID)
+		if !ok {
+			continue
+		}
+		termList := make([]string, 0, len(terms))
+		for t := range terms {
+			termList = append(termList, t)
+		}
+		sort.Strings(termList)
+		hits = append(hits, Hit{Book: bk.Name, BookID: bk.ID, Line: p.Line, Terms: termList})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].BookID != hits[j].BookID {
+			return hits[i].BookID < hits[j].BookID
+		}
+		return hits[i].Line < hits[j].Line
+	})
+
+	for i := range hits {
+		bk, _ := b.bookByID(hits[i].BookID)
+		if number, text, ok := b.verseLine(bk, hits[i].Line); ok {
+			hits[i].Number, hits[i].Text = number, text
+		}
+	}
+	return hits
+}
+
+// postingsForTerm resolves a single query term to the set of postings it
+// matches: the intersection of its word postings, further confirmed
+// against the raw verse text for multi-word phrases since the index only
+// tracks per-line occurrence, not word order.
+func (b *Bible) postingsForTerm(t term) map[posting]bool {
+	if len(t.words) == 0 {
+		return map[posting]bool{}
+	}
+	set := b.postingsForWord(t.words[0])
+	for _, w := range t.words[1:] {
+		set = intersectPostings(set, b.postingsForWord(w))
+	}
+	if t.phrase && len(t.words) > 1 {
+		set = b.filterPhrase(set, t.raw)
+	}
+	return set
+}
+
+func (b *Bible) postingsForWord(word string) map[posting]bool {
+	set := make(map[posting]bool, len(b.searchIdx.Postings[word]))
+	for _, p := range b.searchIdx.Postings[word] {
+		set[p] = true
+	}
+	return set
+}
+
+func intersectPostings(a, b map[posting]bool) map[posting]bool {
+	out := make(map[posting]bool)
+	for p := range a {
+		if b[p] {
+			out[p] = true
+		}
+	}
+	return out
+}
+
+// filterPhrase keeps only the postings whose verse text literally
+// contains raw (case-insensitive), confirming word order for a
+// multi-word phrase term.
+func (b *Bible) filterPhrase(set map[posting]bool, raw string) map[posting]bool {
+	needle := strings.ToLower(raw)
+	out := make(map[posting]bool)
+	for p := range set {
+		bk, ok := b.bookByID(p.BookID)
+		if !ok {
+			continue
+		}
+		if _, text, ok := b.verseLine(bk, p.Line); ok && strings.Contains(strings.ToLower(text), needle) {
+			out[p] = true
+		}
+	}
+	return out
+}
+
+// bookByID looks up one of b's books by its numeric ID.
+func (b *Bible) bookByID(id int) (BookInfo, bool) {
+	for _, bk := range b.books {
+		if bk.ID == id {
+			return bk, true
+		}
+	}
+	return BookInfo{}, false
+}
+
+// verseLine fetches the "chapter:verse" number and text of one line of
+// book bk, the same random-access path used elsewhere via b.vr.
+func (b *Bible) verseLine(bk BookInfo, line int) (number, text string, ok bool) {
+	scanner, err := b.vr.lineScanner(bk, line)
+	if err != nil || !scanner.Scan() {
+		return "", "", false
+	}
+	number, text = splitLine(scanner.Text())
+	return number, text, true
+}
+
+// splitLine splits a "chapter:verse text" line into its number and text.
+func splitLine(line string) (number, text string) {
+	if n, t, ok := strings.Cut(line, " "); ok {
+		return n, t
+	}
+	return "", line
+}
+
+// parseQuery splits query into OR-groups of ANDed terms: "faith OR hope"
+// is two one-term groups; "faith hope" is one two-term group that must
+// both match; a quoted `"have not charity"` is a single phrase term.
+func parseQuery(query string) [][]term {
+	var groups [][]term
+	for _, group := range splitTopLevel(query, " OR ") {
+		groups = append(groups, parseTerms(group))
+	}
+	return groups
+}
+
+// splitTopLevel splits s on sep, ignoring any occurrence of sep inside a
+// double-quoted phrase.
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	inQuotes := false
+	last := 0
+	for i := 0; i+len(sep) <= len(s); i++ {
+		if s[i] == '"' {
+			inQuotes = !inQuotes
+		}
+		if !inQuotes && s[i:i+len(sep)] == sep {
+			parts = append(parts, s[last:i])
+			last = i + len(sep)
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// parseTerms splits one AND-group into its terms: bare words, or quoted
+// phrases spanning several words.
+func parseTerms(group string) []term {
+	var terms []term
+	group = strings.TrimSpace(group)
+	for group != "" {
+		if group[0] == '"' {
+			rest := group[1:]
+			end := strings.IndexByte(rest, '"')
+			if end < 0 {
+				terms = append(terms, term{words: tokenize(rest), phrase: true, raw: rest})
+				break
+			}
+			phrase := rest[:end]
+			terms = append(terms, term{words: tokenize(phrase), phrase: true, raw: phrase})
+			group = strings.TrimSpace(rest[end+1:])
+			continue
+		}
+		next := strings.IndexAny(group, " \t")
+		var word string
+		if next < 0 {
+			word, group = group, ""
+		} else {
+			word, group = group[:next], strings.TrimSpace(group[next:])
+		}
+		if word == "" {
+			continue
+		}
+		terms = append(terms, term{words: tokenize(word), phrase: false, raw: word})
+	}
+	return terms
+}
+
+// tokenize normalizes text into searchable tokens: lowercased,
+// punctuation-stripped, and passed through stem.
+func tokenize(text string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, stem(cur.String()))
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			cur.WriteRune(r)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// stem applies a small Porter-style suffix stripper, not the full
+// algorithm: just enough common suffixes that "faith", "faithful", and
+// "faithfully" share a root so a search for one finds all three.
+func stem(tok string) string {
+	for _, suf := range []string{"ingly", "edly", "fully", "ing", "ful", "ies", "ied", "es", "ed", "s"} {
+		if strings.HasSuffix(tok, suf) && len(tok) > len(suf)+2 {
+			return strings.TrimSuffix(tok, suf)
+		}
+	}
+	return tok
+}
@@ -0,0 +1,167 @@
+// Command searchgen builds search.gob, the compact inverted-index blob
+// that Bible.Search loads at startup.
+//
+// It reads index.txt for the book list, decompresses each N.txt.gz, and
+// tokenizes every verse line (lowercase, strip punctuation, stem) to build
+// a token -> (bookID, line) postings map, which it gob-encodes to
+// search.gob.
+//
+// Run via `go generate ./...` from the randfromkjv directory whenever a
+// *.txt.gz book is added or changed. The tokenizer here must stay in sync
+// with the one in search.go, since a query is normalized the same way at
+// search time.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// posting and searchIndex mirror the types search.go decodes search.gob
+// into.
+type posting struct {
+	BookID int
+	Line   int
+}
+
+type searchIndex struct {
+	Postings map[string][]posting
+}
+
+type bookEntry struct {
+	ID   int
+	File string
+}
+
+func main() {
+	books, err := readBooks("index.txt")
+	if err != nil {
+		log.Fatalf("reading index.txt: %v", err)
+	}
+
+	idx := searchIndex{Postings: make(map[string][]posting)}
+	for _, bk := range books {
+		if err := indexBook(&idx, bk); err != nil {
+			log.Fatalf("%s: %v", bk.File, err)
+		}
+	}
+
+	f, err := os.Create("search.gob")
+	if err != nil {
+		log.Fatalf("creating search.gob: %v", err)
+	}
+	defer f.Close()
+	if err := gob.NewEncoder(f).Encode(idx); err != nil {
+		log.Fatalf("encoding search.gob: %v", err)
+	}
+	fmt.Printf("indexed %d book(s) into search.gob\n", len(books))
+}
+
+// readBooks parses index.txt's "id|name|lineCount" body, skipping any YAML
+// frontmatter, the same way LoadBible does.
+func readBooks(path string) ([]bookEntry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := raw
+	if bytes.HasPrefix(raw, []byte("---\n")) {
+		if parts := bytes.SplitN(raw, []byte("\n---\n"), 2); len(parts) == 2 {
+			content = parts[1]
+		}
+	}
+
+	var books []bookEntry
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		books = append(books, bookEntry{ID: id, File: fmt.Sprintf("%d.txt.gz", id)})
+	}
+	return books, scanner.Err()
+}
+
+// indexBook tokenizes every line of bk's book and adds one posting per
+// distinct token to idx.
+func indexBook(idx *searchIndex, bk bookEntry) error {
+	f, err := os.Open(bk.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("opening gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	line := 0
+	for scanner.Scan() {
+		line++
+		_, text, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			text = scanner.Text()
+		}
+
+		seen := make(map[string]bool)
+		for _, tok := range tokenize(text) {
+			if seen[tok] {
+				continue
+			}
+			seen[tok] = true
+			idx.Postings[tok] = append(idx.Postings[tok], posting{BookID: bk.ID, Line: line})
+		}
+	}
+	return scanner.Err()
+}
+
+// tokenize normalizes text into searchable tokens: lowercased,
+// punctuation-stripped, and passed through stem. Kept identical to
+// search.go's copy so indexing and querying agree on token identity.
+func tokenize(text string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, stem(cur.String()))
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			cur.WriteRune(r)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// stem applies a small Porter-style suffix stripper, not the full
+// algorithm: just enough common suffixes that "faith", "faithful", and
+// "faithfully" share a root so a search for one finds all three.
+func stem(tok string) string {
+	for _, suf := range []string{"ingly", "edly", "fully", "ing", "ful", "ies", "ied", "es", "ed", "s"} {
+		if strings.HasSuffix(tok, suf) && len(tok) > len(suf)+2 {
+			return strings.TrimSuffix(tok, suf)
+		}
+	}
+	return tok
+}
@@ -0,0 +1,48 @@
+// seed.go
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// resolveSeed determines the RNG seed for a single random-verse pick. An
+// explicit seed (from --seed or ?seed=) takes precedence: numeric strings
+// are used as-is, anything else is hashed so any caller-supplied phrase
+// reproduces the same pick. Failing that, daily derives the seed from
+// today's UTC date, optionally salted with narrow so each category gets its
+// own verse of the day. Otherwise a fresh time-based seed is used, same as
+// an ordinary random pick.
+func resolveSeed(explicit string, daily bool, narrow string) int64 {
+	if explicit != "" {
+		if n, err := strconv.ParseInt(explicit, 10, 64); err == nil {
+			return n
+		}
+		return hashSeed(explicit)
+	}
+	if daily {
+		date := time.Now().UTC().Format("2006-01-02")
+		if narrow != "" {
+			date += "|" + narrow
+		}
+		return hashSeed(date)
+	}
+	return time.Now().UnixNano()
+}
+
+// hashSeed folds an arbitrary string down to an int64 seed.
+func hashSeed(s string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
+// rngFor builds a *rand.Rand per resolveSeed's rules and returns the
+// resolved seed alongside it so callers can surface it (e.g. via
+// X-Verse-Seed) for reproducibility.
+func rngFor(explicit string, daily bool, narrow string) (*rand.Rand, int64) {
+	seed := resolveSeed(explicit, daily, narrow)
+	return rand.New(rand.NewSource(seed)), seed
+}
@@ -0,0 +1,234 @@
+// render.go
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VerseEntry is one rendered verse line, split into its "chapter:verse"
+// number and text.
+type VerseEntry struct {
+	Number string `json:"number"`
+	Text   string `json:"text"`
+}
+
+// VerseResult is the data behind a verse lookup (random or by reference),
+// independent of how it ends up rendered to the client.
+type VerseResult struct {
+	Title      string       `json:"-"`
+	Book       string       `json:"book"`
+	BookID     int          `json:"bookId"`
+	Language   string       `json:"language"`
+	LineNumber int          `json:"lineNumber"`
+	LineCount  int          `json:"lineCount"`
+	Verses     []VerseEntry `json:"verses"`
+	Category   string       `json:"category,omitempty"`
+
+	// Seed is the RNG seed that produced this pick (see seedFor), so a
+	// caller can reproduce it via ?seed=. Zero for deterministic lookups
+	// such as a reference, which have no seed to report.
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// verseRenderer renders a VerseResult in one response format.
+type verseRenderer interface {
+	ContentType() string
+	Render(w io.Writer, v VerseResult) error
+}
+
+type htmlVerseRenderer struct{}
+
+func (htmlVerseRenderer) ContentType() string { return "text/html; charset=utf-8" }
+
+func (htmlVerseRenderer) Render(w io.Writer, v VerseResult) error {
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="%s">
+<head>
+  <meta charset="UTF-8">
+  <title>%s</title>
+  <style>
+    body { background: #fafafa; color: #333; font-family: sans-serif; padding: 1rem; line-height: 1.6; }
+    .verse-num { color: #4caf50; font-weight: bold; }
+    .verses p { margin: 0.5em 0; }
+  </style>
+</head>
+<body>
+  <h1>%s</h1>
+  <div class="verses">`, v.Language, html.EscapeString(v.Title), html.EscapeString(v.Title))
+
+	for _, e := range v.Verses {
+		fmt.Fprintf(w, `<p><span class="verse-num">%s</span> %s</p>`+"\n",
+			html.EscapeString(e.Number), e.Text)
+	}
+
+	_, err := fmt.Fprint(w, "</div></body></html>")
+	return err
+}
+
+type jsonVerseRenderer struct{}
+
+func (jsonVerseRenderer) ContentType() string { return "application/json; charset=utf-8" }
+
+func (jsonVerseRenderer) Render(w io.Writer, v VerseResult) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type textVerseRenderer struct{}
+
+func (textVerseRenderer) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (textVerseRenderer) Render(w io.Writer, v VerseResult) error {
+	if _, err := fmt.Fprintf(w, "%s\n\n", v.Title); err != nil {
+		return err
+	}
+	for _, e := range v.Verses {
+		if _, err := fmt.Fprintf(w, "%s %s\n", e.Number, e.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatFor resolves the response format from ?format= or Accept header
+// negotiation: one of "json", "text", or "html" (the default).
+func formatFor(r *http.Request) string {
+	switch f := r.URL.Query().Get("format"); f {
+	case "json", "text", "html":
+		return f
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	case strings.Contains(accept, "text/plain"):
+		return "text"
+	default:
+		return "html"
+	}
+}
+
+// rendererFor picks a verseRenderer per formatFor.
+func rendererFor(r *http.Request) verseRenderer {
+	switch formatFor(r) {
+	case "json":
+		return jsonVerseRenderer{}
+	case "text":
+		return textVerseRenderer{}
+	default:
+		return htmlVerseRenderer{}
+	}
+}
+
+// writeVerseResult renders v in the format negotiated for r and writes it to
+// w, attaching an ETag derived from the rendered body and the given
+// Cache-Control directive. cacheControl should be "no-store" for random
+// picks (each response is meant to differ) and a cacheable directive like
+// "public, max-age=3600" for deterministic lookups such as a reference.
+// HEAD requests get the headers with no body.
+func writeVerseResult(w http.ResponseWriter, r *http.Request, v VerseResult, cacheControl string) {
+	renderer := rendererFor(r)
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := fmt.Sprintf(`"%x"`, sum[:8])
+
+	header := w.Header()
+	header.Set("Content-Type", renderer.ContentType())
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", etag)
+	if v.Seed != 0 {
+		header.Set("X-Verse-Seed", strconv.FormatInt(v.Seed, 10))
+	}
+
+	if match := r.Header.Get("If-None-Match"); match == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(buf.Len()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// SearchResult is the JSON shape of a /search response.
+type SearchResult struct {
+	Query string `json:"query"`
+	Count int    `json:"count"`
+	Hits  []Hit  `json:"hits"`
+}
+
+// writeSearchResults renders search hits in the format negotiated for r.
+// HTML highlights the matched terms using the same green styling as a
+// verse number elsewhere on the site.
+func writeSearchResults(w http.ResponseWriter, r *http.Request, query string, hits []Hit) {
+	switch formatFor(r) {
+	case "json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(SearchResult{Query: query, Count: len(hits), Hits: hits})
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%q — %d result(s)\n\n", query, len(hits))
+		for _, h := range hits {
+			fmt.Fprintf(w, "%s %s %s\n", h.Book, h.Number, h.Text)
+		}
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>Search: %s</title>
+  <style>
+    body { background: #fafafa; color: #333; font-family: sans-serif; padding: 1rem; line-height: 1.6; }
+    .verse-num { color: #4caf50; font-weight: bold; }
+    .hits p { margin: 0.5em 0; }
+    .hit-book { color: #888; font-size: 0.9em; }
+  </style>
+</head>
+<body>
+  <h1>&quot;%s&quot; &mdash; %d result(s)</h1>
+  <div class="hits">`, html.EscapeString(query), html.EscapeString(query), len(hits))
+
+		for _, h := range hits {
+			fmt.Fprintf(w, `<p><span class="hit-book">%s %s</span><br>%s</p>`+"\n",
+				html.EscapeString(h.Book), html.EscapeString(h.Number), highlightHTML(h.Text, h.Terms))
+		}
+
+		fmt.Fprint(w, "</div></body></html>")
+	}
+}
+
+// highlightHTML HTML-escapes text and wraps each case-insensitive
+// occurrence of any of terms in the same <span class="verse-num"> markup
+// used for verse numbers elsewhere, so matches stand out the same green.
+func highlightHTML(text string, terms []string) string {
+	escaped := html.EscapeString(text)
+	if len(terms) == 0 {
+		return escaped
+	}
+	pattern := make([]string, len(terms))
+	for i, t := range terms {
+		pattern[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile(`(?i)(` + strings.Join(pattern, "|") + `)`)
+	return re.ReplaceAllStringFunc(escaped, func(m string) string {
+		return `<span class="verse-num">` + m + `</span>`
+	})
+}